@@ -0,0 +1,78 @@
+// This program burns as much CPU as it can, via pkg/cpuburn, while measuring runtime lag
+// via pkg/lagmon.
+//
+// Runtime lag is measured by waking on a deadline loop at an expected 100ms interval, and
+// then measuring the actual observed elapsed time between ticks. Doing so can measure
+// various effects such as OS scheduling or pressure on the go runtime itself.
+//
+// Reports go out through one or more lagmon.Reporters: a human-readable log (always on), an
+// optional Prometheus /metrics endpoint (-prom-addr), and an optional InfluxDB line-protocol
+// push (-influx-url, -influx-db, -influx-interval).
+//
+// The ticker itself can be wall-clock aligned (-aligned-ticker) so that multiple monitors on
+// the same host sample in phase rather than drifting relative to each other, and jittered
+// (-ticker-jitter) so that aligned monitors don't all collide on the same instant.
+//
+// With -runtime-probe, every tick also samples runtime/metrics (GC pauses, scheduling
+// latency, mutex contention, goroutine count) so outliers can be annotated with a suspected
+// cause instead of reported as unexplained lag.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/aurae-runtime/aurae/pkg/cpuburn"
+	"github.com/aurae-runtime/aurae/pkg/lagmon"
+)
+
+func main() {
+	promAddr := flag.String("prom-addr", "", "if set, serve Prometheus lag_seconds/lag_outliers on this address (e.g. :9090)")
+	influxURL := flag.String("influx-url", "", "if set, push metrics to this InfluxDB HTTP API (e.g. http://localhost:8086)")
+	influxDB := flag.String("influx-db", "aurae", "InfluxDB database to write to")
+	influxInterval := flag.Duration("influx-interval", 10*time.Second, "how often to push to InfluxDB")
+	aligned := flag.Bool("aligned-ticker", false, "phase-lock ticks to wall-clock multiples of the tick interval")
+	tickerJitter := flag.Duration("ticker-jitter", 0, "uniform random jitter window applied to each scheduled tick")
+	runtimeProbeEnabled := flag.Bool("runtime-probe", false, "sample runtime/metrics on every tick and annotate outliers with a suspected cause")
+	threshold := flag.Duration("outlier-threshold", 2*time.Millisecond, "floor for the IQR-based outlier rule")
+	flag.Parse()
+
+	opts := []lagmon.Option{
+		lagmon.WithJitter(*tickerJitter),
+		lagmon.WithOutlierThreshold(*threshold),
+	}
+	if *runtimeProbeEnabled {
+		opts = append(opts, lagmon.WithRuntimeProbe(*threshold))
+	}
+
+	mon := lagmon.NewMonitor(100*time.Millisecond, 10*time.Second, opts...)
+	if *aligned {
+		mon.TickerKind = lagmon.AlignedTicker
+	}
+	mon.Start()
+	defer mon.Stop()
+
+	numProcs := runtime.GOMAXPROCS(0)
+	go cpuburn.Run(context.Background(), numProcs)
+	log.Printf("started %v burners", numProcs)
+
+	if *promAddr != "" {
+		prom := lagmon.NewPromReporter(mon, *promAddr)
+		go lagmon.RunReporter(mon, prom, time.Second)
+		log.Printf("serving prometheus metrics on %v", *promAddr)
+	}
+
+	if *influxURL != "" {
+		influx := &lagmon.InfluxReporter{URL: *influxURL, DB: *influxDB, Client: &http.Client{Timeout: 5 * time.Second}}
+		go lagmon.RunReporter(mon, influx, *influxInterval)
+		log.Printf("pushing influx metrics to %v db=%v every %v", *influxURL, *influxDB, *influxInterval)
+	}
+
+	// The human-readable log reporter runs in the main goroutine so the process stays up
+	// for as long as it's reporting.
+	lagmon.RunReporter(mon, lagmon.LogReporter{}, time.Second)
+}
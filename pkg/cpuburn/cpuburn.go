@@ -0,0 +1,54 @@
+// Package cpuburn keeps every available CPU busy computing random collatz trajectories, so
+// that other code (notably pkg/lagmon) can measure how scheduler and runtime lag behave
+// under sustained CPU pressure.
+package cpuburn
+
+import (
+	"context"
+	"math/rand"
+)
+
+// Run starts numWorkers burner goroutines and blocks until ctx is canceled, at which point
+// all workers stop and Run returns.
+func Run(ctx context.Context, numWorkers int) {
+	done := make(chan struct{})
+	for i := 0; i < numWorkers; i += 1 {
+		go func() {
+			burn(ctx)
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < numWorkers; i += 1 {
+		<-done
+	}
+}
+
+// burn computes collatz trajectories for random starting points until ctx is canceled.
+func burn(ctx context.Context) {
+	max := 0
+	var last []int
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		var path []int
+		n := rand.Intn(1_000_000_000)
+		for n > 1 {
+			path = append(path, n)
+			if n%2 == 0 {
+				n /= 2
+			} else {
+				n = 3*n + 1
+			}
+		}
+
+		last = path
+		if m := len(last); m > max {
+			max = m
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package lagmon
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Histogram bucket layout: bucket 0 absorbs anything under histBaseNanos, and bucket i>=1
+// covers [histBaseNanos*growth^(i-1), histBaseNanos*growth^i). A growth factor of 1.15 gives
+// sub-15% relative error at any percentile while keeping the bucket count small, the same
+// tradeoff golang.org/x/net/trace's latency histogram makes.
+const (
+	histGrowthFactor = 1.15
+	histBaseNanos    = 1000 // 1µs
+	histNumBuckets   = 160  // covers up to ~65 minutes before saturating the last bucket
+)
+
+func bucketIndex(d time.Duration) int {
+	ns := float64(d.Nanoseconds())
+	if ns < histBaseNanos {
+		return 0
+	}
+	i := int(math.Log(ns/histBaseNanos)/math.Log(histGrowthFactor)) + 1
+	if i >= histNumBuckets {
+		i = histNumBuckets - 1
+	}
+	return i
+}
+
+// bucketBounds returns the half-open [lo, hi) range a bucket covers.
+func bucketBounds(i int) (lo, hi time.Duration) {
+	if i == 0 {
+		return 0, histBaseNanos * time.Nanosecond
+	}
+	lo = time.Duration(histBaseNanos * math.Pow(histGrowthFactor, float64(i-1)))
+	hi = time.Duration(histBaseNanos * math.Pow(histGrowthFactor, float64(i)))
+	return lo, hi
+}
+
+// lagHistogram is a fixed-size, constant-memory HDR-style histogram of lag measurements.
+// Bucket counters are updated with atomic.AddUint64 so Percentiles and Buckets can read them
+// without taking a lock; count/sum/sumSq back Mean and StdDev and are guarded by statsLock
+// since there's no portable lock-free float64 add, so add itself still locks once per sample.
+type lagHistogram struct {
+	buckets [histNumBuckets]uint64
+
+	statsLock sync.Mutex
+	count     uint64
+	sum       time.Duration
+	sumSq     float64 // sum of squared nanoseconds
+}
+
+func (h *lagHistogram) add(d time.Duration) {
+	atomic.AddUint64(&h.buckets[bucketIndex(d)], 1)
+
+	ns := float64(d.Nanoseconds())
+	h.statsLock.Lock()
+	h.count++
+	h.sum += d
+	h.sumSq += ns * ns
+	h.statsLock.Unlock()
+}
+
+// Reset zeroes the histogram in place, for callers that swap two histograms on a timer to
+// get a rolling-window view (à la go-metrics' ResettingTimer) rather than a lifetime one.
+func (h *lagHistogram) Reset() {
+	for i := range h.buckets {
+		atomic.StoreUint64(&h.buckets[i], 0)
+	}
+	h.statsLock.Lock()
+	h.count, h.sum, h.sumSq = 0, 0, 0
+	h.statsLock.Unlock()
+}
+
+// Merge folds other's counts into h, so that per-CPU or per-goroutine histograms can be
+// aggregated cheaply instead of sharing one contended histogram.
+func (h *lagHistogram) Merge(other *lagHistogram) {
+	for i := range h.buckets {
+		if n := atomic.LoadUint64(&other.buckets[i]); n > 0 {
+			atomic.AddUint64(&h.buckets[i], n)
+		}
+	}
+
+	other.statsLock.Lock()
+	count, sum, sumSq := other.count, other.sum, other.sumSq
+	other.statsLock.Unlock()
+
+	h.statsLock.Lock()
+	h.count += count
+	h.sum += sum
+	h.sumSq += sumSq
+	h.statsLock.Unlock()
+}
+
+func (h *lagHistogram) Count() uint64 {
+	h.statsLock.Lock()
+	defer h.statsLock.Unlock()
+	return h.count
+}
+
+func (h *lagHistogram) Mean() time.Duration {
+	h.statsLock.Lock()
+	defer h.statsLock.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+func (h *lagHistogram) Sum() time.Duration {
+	h.statsLock.Lock()
+	defer h.statsLock.Unlock()
+	return h.sum
+}
+
+func (h *lagHistogram) StdDev() time.Duration {
+	h.statsLock.Lock()
+	count, sum, sumSq := h.count, h.sum, h.sumSq
+	h.statsLock.Unlock()
+	if count == 0 {
+		return 0
+	}
+	mean := float64(sum.Nanoseconds()) / float64(count)
+	variance := sumSq/float64(count) - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+	return time.Duration(math.Sqrt(variance))
+}
+
+// Bucket is a snapshot of one histogram bucket's cumulative count, suitable for emitting a
+// Prometheus-style cumulative histogram (`le` buckets).
+type Bucket struct {
+	UpperBound time.Duration
+	Count      uint64 // cumulative count of samples < UpperBound; the last bucket also counts every sample past it
+}
+
+// Buckets snapshots the cumulative bucket counts in one pass over the atomic counters.
+func (h *lagHistogram) Buckets() []Bucket {
+	out := make([]Bucket, histNumBuckets)
+	var cum uint64
+	for i := range h.buckets {
+		cum += atomic.LoadUint64(&h.buckets[i])
+		_, hi := bucketBounds(i)
+		out[i] = Bucket{UpperBound: hi, Count: cum}
+	}
+	return out
+}
+
+// Percentiles returns, for each p in ps (0..1), the lag value at that percentile, linearly
+// interpolated across the bucket boundaries straddling it using the cumulative count. It
+// snapshots bucket counts with a single pass over the atomic counters, so it's safe to call
+// concurrently with add.
+func (h *lagHistogram) Percentiles(ps ...float64) []time.Duration {
+	counts := make([]uint64, histNumBuckets)
+	var total uint64
+	for i := range h.buckets {
+		c := atomic.LoadUint64(&h.buckets[i])
+		counts[i] = c
+		total += c
+	}
+
+	out := make([]time.Duration, len(ps))
+	if total == 0 {
+		return out
+	}
+
+	for pi, p := range ps {
+		if p <= 0 {
+			// target=0 would trivially satisfy next>=target at the very first bucket
+			// regardless of whether it holds any samples, always returning 0. p=0 means
+			// "the minimum", so find the first bucket that actually has samples instead.
+			for i, c := range counts {
+				if c > 0 {
+					lo, _ := bucketBounds(i)
+					out[pi] = lo
+					break
+				}
+			}
+			continue
+		}
+
+		target := p * float64(total)
+		var cum uint64
+		for i, c := range counts {
+			next := cum + c
+			if float64(next) >= target || i == histNumBuckets-1 {
+				lo, hi := bucketBounds(i)
+				frac := 0.0
+				if c > 0 {
+					frac = (target - float64(cum)) / float64(c)
+				}
+				out[pi] = lo + time.Duration(frac*float64(hi-lo))
+				break
+			}
+			cum = next
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package lagmon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketIndexBoundaries(t *testing.T) {
+	cases := []struct {
+		name string
+		d    time.Duration
+		want int
+	}{
+		{"zero", 0, 0},
+		{"exactly base", histBaseNanos * time.Nanosecond, 1},
+		{"just over base", histBaseNanos*time.Nanosecond + 1, 1},
+		{"negative clamps to 0", -time.Millisecond, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := bucketIndex(c.d); got != c.want {
+				t.Errorf("bucketIndex(%v) = %d, want %d", c.d, got, c.want)
+			}
+		})
+	}
+
+	// A value comfortably past a bucket's upper bound lands in a later bucket, not the same one.
+	_, hi := bucketBounds(5)
+	if got := bucketIndex(hi + time.Microsecond); got <= 5 {
+		t.Errorf("bucketIndex(past bucket 5's hi bound) = %d, want > 5", got)
+	}
+
+	if got := bucketIndex(24 * time.Hour); got != histNumBuckets-1 {
+		t.Errorf("bucketIndex(huge duration) = %d, want last bucket %d", got, histNumBuckets-1)
+	}
+}
+
+func TestPercentilesEmpty(t *testing.T) {
+	var h lagHistogram
+	got := h.Percentiles(0, 0.5, 1)
+	for i, d := range got {
+		if d != 0 {
+			t.Errorf("Percentiles on empty histogram [%d] = %v, want 0", i, d)
+		}
+	}
+}
+
+func TestPercentilesMinReflectsFirstNonEmptyBucket(t *testing.T) {
+	var h lagHistogram
+	h.add(10 * time.Millisecond)
+	h.add(20 * time.Millisecond)
+	h.add(30 * time.Millisecond)
+
+	got := h.Percentiles(0)
+	lo, _ := bucketBounds(bucketIndex(10 * time.Millisecond))
+	if got[0] != lo {
+		t.Errorf("Percentiles(0) = %v, want %v (lower bound of the first non-empty bucket)", got[0], lo)
+	}
+}
+
+func TestPercentilesMaxIsLastBucketWithSamples(t *testing.T) {
+	var h lagHistogram
+	h.add(time.Millisecond)
+	h.add(100 * time.Millisecond)
+
+	got := h.Percentiles(1)
+	_, hi := bucketBounds(bucketIndex(100 * time.Millisecond))
+	if got[0] > hi {
+		t.Errorf("Percentiles(1) = %v, want <= %v (upper bound of the bucket holding the max sample)", got[0], hi)
+	}
+}
+
+func TestPercentilesMonotonic(t *testing.T) {
+	var h lagHistogram
+	for i := 1; i <= 100; i++ {
+		h.add(time.Duration(i) * time.Millisecond)
+	}
+
+	got := h.Percentiles(0, 0.25, 0.5, 0.75, 1)
+	for i := 1; i < len(got); i++ {
+		if got[i] < got[i-1] {
+			t.Errorf("Percentiles not monotonic: %v", got)
+			break
+		}
+	}
+}
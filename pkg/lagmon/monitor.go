@@ -0,0 +1,325 @@
+// Package lagmon measures scheduler/runtime lag: the gap between when a tick was expected
+// to fire and when it actually did. A Monitor samples this on its own deadline loop, keeps a
+// constant-memory histogram of the results, and can annotate lag spikes with a suspected
+// cause (GC, scheduling, lock contention) via an attached runtime/metrics probe.
+//
+// Monitor is meant to be embedded in other Aurae components (nested cell benchmarks, CI
+// latency regressions, PID1 health checks) rather than only driven from the
+// cpu-burn-and-measure-runtime-lag binary in cmd/.
+package lagmon
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// TickerKind selects how a Monitor schedules its ticks. AlignedTicker phase-locks to
+// wall-clock multiples of the tick interval (e.g. exactly on :00.000, :00.100, ...) so that
+// independent monitors on the same host sample at the same moments instead of drifting into
+// or out of phase with each other. UnalignedTicker keeps the old behavior of anchoring to
+// whenever the monitor started.
+type TickerKind int
+
+const (
+	UnalignedTicker TickerKind = iota
+	AlignedTicker
+)
+
+// defaultOutlierThreshold floors the IQR-outlier rule so that a near-constant lag
+// distribution (near-zero IQR) doesn't flag everything above the median as an outlier.
+const defaultOutlierThreshold = 2 * time.Millisecond
+
+// Option configures a Monitor at construction time.
+type Option func(*Monitor)
+
+// WithJitter adds a uniform random offset in [0, d) to every scheduled tick, so that
+// multiple tickers using the same interval (and, for AlignedTicker, the same phase) don't
+// collide and contend for the same OS timer slot.
+func WithJitter(d time.Duration) Option {
+	return func(mon *Monitor) { mon.jitter = d }
+}
+
+// WithRuntimeProbe attaches a runtime/metrics probe to the monitor so every tick also
+// samples GC, scheduling, and mutex-contention series, letting outlier reports be annotated
+// with a suspected cause instead of just "something is slow". floor sets the same
+// IQR-outlier floor the lag monitor itself uses, applied to each duration-valued
+// runtime/metrics series; the goroutine-count series uses goroutineOutlierFloor instead.
+func WithRuntimeProbe(floor time.Duration) Option {
+	return func(mon *Monitor) {
+		mon.probe = newRuntimeProbe()
+		mon.probeFloor = floor
+	}
+}
+
+// WithOutlierThreshold overrides the floor Snapshot and Subscribe use when deciding whether
+// a tick is a lag outlier; see defaultOutlierThreshold.
+func WithOutlierThreshold(d time.Duration) Option {
+	return func(mon *Monitor) { mon.threshold = d }
+}
+
+// WithReportInterval sets how often Subscribe pushes a Report; the default is one second.
+func WithReportInterval(d time.Duration) Option {
+	return func(mon *Monitor) { mon.reportInterval = d }
+}
+
+// NewMonitor constructs a Monitor that ticks every `every` and keeps roughly `keepLast`
+// worth of raw samples in its recent-sample ring buffer (used only for the outlier
+// side-channel; aggregate stats come from the constant-memory histogram).
+func NewMonitor(every, keepLast time.Duration, opts ...Option) *Monitor {
+	bufferCap := keepLast / every
+	mon := &Monitor{
+		every:          every,
+		buffer:         make([]Sample, bufferCap),
+		threshold:      defaultOutlierThreshold,
+		reportInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(mon)
+	}
+	return mon
+}
+
+// nextAlignedTime rounds now up to the next wall-clock multiple of every (e.g. every=100ms
+// rounds up to the next :00.000, :00.100, :00.200, ...).
+func nextAlignedTime(now time.Time, every time.Duration) time.Time {
+	if every <= 0 {
+		return now
+	}
+	rem := now.UnixNano() % every.Nanoseconds()
+	if rem == 0 {
+		return now
+	}
+	return now.Add(every - time.Duration(rem))
+}
+
+func randJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// Monitor measures the lag between expected and actual tick times on its own deadline loop.
+type Monitor struct {
+	every      time.Duration
+	TickerKind TickerKind
+	jitter     time.Duration
+
+	threshold      time.Duration
+	reportInterval time.Duration
+
+	probe      *runtimeProbe
+	probeFloor time.Duration
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+
+	hist lagHistogram
+
+	// buffer is a ring of recent Samples, kept only as a side-channel so outlier ticks can
+	// still be reported with their raw timestamps and skew; it is not the source of
+	// percentile or boxplot stats, which come from hist instead.
+	bufferLock sync.Mutex
+	buffer     []Sample
+	cur        int
+	full       bool
+}
+
+// Sample is one recorded tick: when it fired, how that compares to the previous tick and to
+// its own scheduled deadline, and (if a runtime/metrics probe is attached) what changed in
+// the runtime around it.
+type Sample struct {
+	Start    time.Time
+	End      time.Time
+	Actual   time.Duration // gap: time since the previous tick
+	Expected time.Duration // the monitor's configured tick interval
+
+	ExpectedTick time.Time     // the aligned/jittered wall-clock deadline this tick targeted
+	Skew         time.Duration // End - ExpectedTick: how far this tick fired from its own deadline
+
+	Runtime RuntimeDelta // runtime/metrics deltas sampled alongside this tick, if a probe is attached
+	Cause   string       // dominant suspected cause of this tick's lag, e.g. "gc-pause: 3.2ms"; "" if unremarkable
+}
+
+// collect records one tick: actual is when the tick fired, last is when the previous one
+// fired, and expected is the wall-clock deadline this tick was scheduled for (which may
+// differ from last+every by whatever jitter was applied). The histogram tracks the gap
+// since the last tick; skew (how far the tick fired from its own deadline) is recorded
+// separately in the ring buffer so the two effects aren't conflated in reports. rt and cause
+// are the runtime/metrics sample and suspected-cause annotation for this tick, if a
+// runtimeProbe is attached; cause is "" otherwise.
+func (mon *Monitor) collect(last, actual, expected time.Time, rt RuntimeDelta, cause string) {
+	gap := actual.Sub(last)
+	mon.hist.add(gap)
+
+	mon.bufferLock.Lock()
+	mon.buffer[mon.cur] = Sample{
+		Start:        last,
+		End:          actual,
+		Actual:       gap,
+		Expected:     mon.every,
+		ExpectedTick: expected,
+		Skew:         actual.Sub(expected),
+		Runtime:      rt,
+		Cause:        cause,
+	}
+	mon.cur += 1
+
+	if mon.cur >= len(mon.buffer) {
+		mon.cur = 0
+		mon.full = true
+	}
+	mon.bufferLock.Unlock()
+}
+
+// monitor runs an absolute-deadline loop: the n-th deadline is anchor+n*every (plus jitter),
+// computed once up front, rather than scheduled relative to the previous tick's actual fire
+// time. That keeps scheduling delays from accumulating into long-run drift the way a
+// time.Ticker driven purely by "wake, then wait `every` again" would.
+func (mon *Monitor) monitor() {
+	now := time.Now()
+	anchor := now
+	if mon.TickerKind == AlignedTicker {
+		anchor = nextAlignedTime(now, mon.every)
+	}
+
+	last := now
+	for n := 0; ; n++ {
+		expected := anchor.Add(time.Duration(n) * mon.every)
+		deadline := expected.Add(randJitter(mon.jitter))
+
+		select {
+		case <-mon.stopCh:
+			return
+		case <-time.After(time.Until(deadline)):
+		}
+
+		actual := time.Now()
+
+		var rt RuntimeDelta
+		var cause string
+		if mon.probe != nil {
+			rt = mon.probe.sample()
+			cause = mon.probe.Cause(rt, mon.probeFloor)
+		}
+
+		mon.collect(last, actual, deadline, rt, cause)
+		last = actual
+	}
+}
+
+// Start begins sampling in a background goroutine. It's a no-op if the monitor is already
+// running.
+func (mon *Monitor) Start() {
+	if mon.stopCh == nil {
+		mon.stopCh = make(chan struct{})
+		go mon.monitor()
+	}
+}
+
+// Stop halts the sampling goroutine started by Start.
+func (mon *Monitor) Stop() {
+	if mon.stopCh != nil {
+		mon.stopOnce.Do(func() { close(mon.stopCh) })
+	}
+}
+
+// Percentiles reports lag values at the given percentiles (0..1), computed from the
+// constant-memory histogram rather than sorting the recent-sample buffer.
+func (mon *Monitor) Percentiles(ps ...float64) []time.Duration {
+	return mon.hist.Percentiles(ps...)
+}
+
+func (mon *Monitor) Count() uint64        { return mon.hist.Count() }
+func (mon *Monitor) Sum() time.Duration   { return mon.hist.Sum() }
+func (mon *Monitor) Mean() time.Duration  { return mon.hist.Mean() }
+func (mon *Monitor) StdDev() time.Duration { return mon.hist.StdDev() }
+func (mon *Monitor) Buckets() []Bucket    { return mon.hist.Buckets() }
+func (mon *Monitor) Reset()               { mon.hist.Reset() }
+func (mon *Monitor) Merge(other *Monitor)  { mon.hist.Merge(&other.hist) }
+
+// Data returns the recent raw samples still held in the ring buffer. It exists purely as an
+// outlier side-channel; use Percentiles, Mean, and StdDev for aggregate stats over the full
+// run.
+func (mon *Monitor) Data() []Sample {
+	mon.bufferLock.Lock()
+	defer mon.bufferLock.Unlock()
+
+	data := make([]Sample, 0, len(mon.buffer))
+	max := mon.cur
+	if mon.full {
+		max = len(mon.buffer)
+	}
+
+	for i := 0; i < max; i += 1 {
+		j := i
+		if mon.full {
+			j = (mon.cur + i) % len(mon.buffer)
+		}
+		data = append(data, mon.buffer[j])
+	}
+
+	return data
+}
+
+// Report is the point-in-time view Snapshot and Subscribe produce: boxplot stats over the
+// full histogram, plus the raw outlier samples still held in the recent-sample ring buffer.
+type Report struct {
+	Count                   uint64
+	Mean, StdDev            time.Duration
+	Min, Q25, Q50, Q75, Max time.Duration
+	Threshold, Hi           time.Duration
+	NumOutliers             int
+	OutlierSamples          []Sample
+}
+
+// Snapshot computes a Report from the current histogram and recent-sample buffer, without
+// logging or otherwise side-effecting: quantiles from the histogram, then an IQR-based
+// outlier threshold walked against the recent-sample buffer to find and attach the
+// offending raw rows.
+func (mon *Monitor) Snapshot() Report {
+	snap := Report{Count: mon.Count(), Mean: mon.Mean(), StdDev: mon.StdDev(), Threshold: mon.threshold}
+	if snap.Count == 0 {
+		return snap
+	}
+
+	qs := mon.Percentiles(0, 0.25, 0.5, 0.75, 1)
+	snap.Min, snap.Q25, snap.Q50, snap.Q75, snap.Max = qs[0], qs[1], qs[2], qs[3], qs[4]
+
+	if snap.Max-snap.Min < mon.threshold {
+		return snap
+	}
+
+	iqr := snap.Q75 - snap.Q25
+	add := 3 * iqr
+	if add < mon.threshold {
+		add = mon.threshold
+	}
+	snap.Hi = snap.Q50 + add
+
+	for _, s := range mon.Data() {
+		if s.Actual >= snap.Hi {
+			snap.NumOutliers += 1
+			snap.OutlierSamples = append(snap.OutlierSamples, s)
+		}
+	}
+
+	return snap
+}
+
+// Subscribe returns a channel that receives a Report every reportInterval (see
+// WithReportInterval; one second by default). The channel is buffered by one and drops a
+// report rather than blocking if the subscriber isn't keeping up.
+func (mon *Monitor) Subscribe() <-chan Report {
+	ch := make(chan Report, 1)
+	go func() {
+		for range time.Tick(mon.reportInterval) {
+			select {
+			case ch <- mon.Snapshot():
+			default:
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,148 @@
+package lagmon
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reporter emits a Report to some sink. Implementations model the go-metrics reporter
+// pattern: each one runs on its own ticker goroutine (see RunReporter) and multiple
+// reporters can run concurrently off the same Monitor.
+type Reporter interface {
+	Report(snap Report)
+}
+
+// RunReporter wakes r on every tick of interval, reads a fresh Report from mon, and hands it
+// off. It never returns; callers that want more than one reporter run it in its own
+// goroutine.
+func RunReporter(mon *Monitor, r Reporter, interval time.Duration) {
+	for range time.Tick(interval) {
+		r.Report(mon.Snapshot())
+	}
+}
+
+// LogReporter emits the human-readable "[lag report] ..." log lines.
+type LogReporter struct{}
+
+func (LogReporter) Report(snap Report) {
+	if snap.Count == 0 {
+		return
+	}
+
+	if snap.Max-snap.Min < snap.Threshold {
+		log.Printf("[lag report] min:%v max:%v", snap.Min, snap.Max)
+		return
+	}
+
+	if snap.NumOutliers == 0 {
+		log.Printf("[lag report] min:%v max:%v box:[ %v %v %v ] no outliers within threshold:%v",
+			snap.Min, snap.Max, snap.Q25, snap.Q50, snap.Q75, snap.Threshold)
+		return
+	}
+
+	log.Printf(
+		"[lag report] min:%v max:%v box:[ %v %v %v ] hi:%v hiOutliers:%v %.1f%%",
+		snap.Min, snap.Max,
+		snap.Q25, snap.Q50, snap.Q75, snap.Hi,
+		snap.NumOutliers,
+		float64(snap.NumOutliers)/float64(snap.Count)*100,
+	)
+	for _, s := range snap.OutlierSamples {
+		log.Printf("%+v", s)
+	}
+}
+
+// PromReporter serves the latest Report as a Prometheus text-exposition /metrics endpoint:
+// lag_seconds as a cumulative histogram, plus lag_outliers as a gauge. Report just updates
+// the cached snapshot; the HTTP handler formats it lazily on scrape.
+type PromReporter struct {
+	mu   sync.Mutex
+	last Report
+	mon  *Monitor
+}
+
+// NewPromReporter starts serving /metrics on addr and returns a PromReporter whose Report
+// method should be fed a Report on whatever interval the caller chooses (see RunReporter).
+func NewPromReporter(mon *Monitor, addr string) *PromReporter {
+	r := &PromReporter{mon: mon}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", r.serve)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("prometheus reporter: %v", err)
+		}
+	}()
+	return r
+}
+
+func (r *PromReporter) Report(snap Report) {
+	r.mu.Lock()
+	r.last = snap
+	r.mu.Unlock()
+}
+
+func (r *PromReporter) serve(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	snap := r.last
+	r.mu.Unlock()
+
+	// lag_seconds_bucket{le="+Inf"} and lag_seconds_count must come from the same read as the
+	// finite buckets, not a cached snapshot that's up to one RunReporter tick stale — otherwise
+	// the finite buckets can grow past a frozen +Inf/count between ticks, which breaks the
+	// histogram invariant that +Inf (the cumulative total) is >= every finite bucket. The
+	// last bucket's cumulative count is exactly that total, since every value saturates into
+	// some bucket, so derive both from this one live Buckets() read instead of a second call.
+	buckets := r.mon.Buckets()
+	var total uint64
+	if len(buckets) > 0 {
+		total = buckets[len(buckets)-1].Count
+	}
+
+	fmt.Fprintln(w, "# TYPE lag_seconds histogram")
+	for _, b := range buckets {
+		fmt.Fprintf(w, "lag_seconds_bucket{le=\"%g\"} %d\n", b.UpperBound.Seconds(), b.Count)
+	}
+	fmt.Fprintf(w, "lag_seconds_bucket{le=\"+Inf\"} %d\n", total)
+	fmt.Fprintf(w, "lag_seconds_sum %f\n", r.mon.Sum().Seconds())
+	fmt.Fprintf(w, "lag_seconds_count %d\n", total)
+
+	// lag_outliers counts outliers still held in the recent-sample ring buffer (see Report's
+	// OutlierSamples), which falls as old outliers age out — it's a gauge, not a counter,
+	// since a Prometheus _total must never decrease or rate()/increase() on it breaks.
+	fmt.Fprintln(w, "# TYPE lag_outliers gauge")
+	fmt.Fprintf(w, "lag_outliers %d\n", snap.NumOutliers)
+}
+
+// InfluxReporter pushes a Report as an InfluxDB line-protocol write on every Report call.
+type InfluxReporter struct {
+	URL    string
+	DB     string
+	Client *http.Client
+}
+
+func (r *InfluxReporter) Report(snap Report) {
+	if snap.Count == 0 {
+		return
+	}
+
+	line := fmt.Sprintf(
+		"lag_seconds count=%d,mean=%f,stddev=%f,min=%f,q25=%f,q50=%f,q75=%f,max=%f,outliers=%di %d\n",
+		snap.Count, snap.Mean.Seconds(), snap.StdDev.Seconds(),
+		snap.Min.Seconds(), snap.Q25.Seconds(), snap.Q50.Seconds(), snap.Q75.Seconds(), snap.Max.Seconds(),
+		snap.NumOutliers,
+		time.Now().UnixNano(),
+	)
+
+	endpoint := fmt.Sprintf("%s/write?db=%s", strings.TrimRight(r.URL, "/"), url.QueryEscape(r.DB))
+	resp, err := r.Client.Post(endpoint, "text/plain", strings.NewReader(line))
+	if err != nil {
+		log.Printf("influx reporter: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
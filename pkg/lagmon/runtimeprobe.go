@@ -0,0 +1,214 @@
+package lagmon
+
+import (
+	"fmt"
+	"math"
+	"runtime/metrics"
+	"time"
+)
+
+// runtimeProbeMetrics are the runtime/metrics series sampled on every tick: scheduling
+// latency, GC pause time, mutex contention, GC CPU share, and live goroutine count. These
+// are the series most likely to explain a lag spike as something other than "unknown".
+var runtimeProbeMetrics = []string{
+	"/sched/latencies:seconds",
+	"/gc/pauses:seconds",
+	"/sync/mutex/wait/total:seconds",
+	"/cpu/classes/gc/total:cpu-seconds",
+	"/sched/goroutines:goroutines",
+}
+
+// RuntimeDelta is what changed in the sampled runtime/metrics series since the previous tick.
+type RuntimeDelta struct {
+	SchedLatency   time.Duration
+	GCPause        time.Duration
+	MutexWait      time.Duration
+	GCCPU          time.Duration
+	Goroutines     int64
+	GoroutineDelta int64
+}
+
+// runtimeProbe samples runtime/metrics on every tick and keeps a small IQR-outlier history
+// per series, so a lag spike can be annotated with its most likely cause instead of left
+// unexplained.
+type runtimeProbe struct {
+	samples []metrics.Sample // pre-sized and reused so Read doesn't allocate on the hot path
+
+	// started is false until the first sample call has run. The lastXxx fields start at
+	// zero, so a delta computed on the very first call isn't a per-tick delta at all — it's
+	// the full cumulative total since process start. That value is only good for seeding
+	// lastXxx; it must not be recorded into the histograms below, or it permanently skews
+	// their sum/mean/percentiles with one wildly inflated sample.
+	started bool
+
+	lastSchedLatency time.Duration
+	lastGCPause      time.Duration
+	lastMutexWait    time.Duration
+	lastGCCPU        time.Duration
+	lastGoroutines   int64
+
+	schedLatencyHist lagHistogram
+	gcPauseHist      lagHistogram
+	mutexWaitHist    lagHistogram
+	goroutineHist    lagHistogram // abs(GoroutineDelta) encoded as microseconds, reusing lagHistogram purely as a magnitude bucketer
+}
+
+func newRuntimeProbe() *runtimeProbe {
+	samples := make([]metrics.Sample, len(runtimeProbeMetrics))
+	for i, name := range runtimeProbeMetrics {
+		samples[i].Name = name
+	}
+	return &runtimeProbe{samples: samples}
+}
+
+// histSum approximates the total time represented by a runtime/metrics duration histogram,
+// by summing each bucket's midpoint weighted by its count. The top bucket's upper bound is
+// +Inf, so it's weighted by its lower bound instead of a midpoint.
+func histSum(h *metrics.Float64Histogram) time.Duration {
+	var total float64
+	for i, count := range h.Counts {
+		if count == 0 {
+			continue
+		}
+		lo, hi := h.Buckets[i], h.Buckets[i+1]
+		mid := lo
+		if !math.IsInf(hi, 1) {
+			mid = (lo + hi) / 2
+		}
+		total += mid * float64(count)
+	}
+	return time.Duration(total * float64(time.Second))
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// sample reads the runtime/metrics series into the probe's pre-sized slice and returns the
+// deltas since the last call, recording every delta except GCCPU into its own outlier-history
+// histogram. The
+// first call only seeds that baseline and records nothing, since there is no "last call" to
+// diff against yet.
+func (p *runtimeProbe) sample() RuntimeDelta {
+	metrics.Read(p.samples)
+
+	var d RuntimeDelta
+	for _, s := range p.samples {
+		switch s.Name {
+		case "/sched/latencies:seconds":
+			total := histSum(s.Value.Float64Histogram())
+			d.SchedLatency = total - p.lastSchedLatency
+			p.lastSchedLatency = total
+		case "/gc/pauses:seconds":
+			total := histSum(s.Value.Float64Histogram())
+			d.GCPause = total - p.lastGCPause
+			p.lastGCPause = total
+		case "/sync/mutex/wait/total:seconds":
+			v := time.Duration(s.Value.Float64() * float64(time.Second))
+			d.MutexWait = v - p.lastMutexWait
+			p.lastMutexWait = v
+		case "/cpu/classes/gc/total:cpu-seconds":
+			v := time.Duration(s.Value.Float64() * float64(time.Second))
+			d.GCCPU = v - p.lastGCCPU
+			p.lastGCCPU = v
+		case "/sched/goroutines:goroutines":
+			n := int64(s.Value.Uint64())
+			d.Goroutines = n
+			d.GoroutineDelta = n - p.lastGoroutines
+			p.lastGoroutines = n
+		}
+	}
+
+	// The first call's "delta" fields are actually cumulative totals since process start, not
+	// per-tick deltas, since lastXxx all start at zero; the switch above already used them to
+	// seed lastXxx for future calls. Zero them out of the returned RuntimeDelta too, not just
+	// the histograms below, so a lag outlier on the very first tick can't surface a wildly
+	// inflated "delta" in Sample.Runtime and the logs/reporters that print it.
+	if !p.started {
+		d.SchedLatency, d.GCPause, d.MutexWait, d.GCCPU, d.GoroutineDelta = 0, 0, 0, 0, 0
+	} else {
+		p.schedLatencyHist.add(d.SchedLatency)
+		p.gcPauseHist.add(d.GCPause)
+		p.mutexWaitHist.add(d.MutexWait)
+		p.goroutineHist.add(time.Duration(absInt64(d.GoroutineDelta)) * time.Microsecond)
+	}
+	p.started = true
+
+	return d
+}
+
+// outlierThreshold returns the "hi" cutoff a distribution's IQR-outlier rule flags against:
+// the median plus 3x the interquartile range, floored so that near-constant distributions
+// don't flag everything above the median. This is the same rule the lag monitor itself uses.
+func outlierThreshold(h *lagHistogram, floor time.Duration) time.Duration {
+	qs := h.Percentiles(0.25, 0.5, 0.75)
+	q25, q50, q75 := qs[0], qs[1], qs[2]
+	add := 3 * (q75 - q25)
+	if add < floor {
+		add = floor
+	}
+	return q50 + add
+}
+
+// goroutineOutlierFloor floors the goroutine-delta IQR rule in raw goroutine-count units. A
+// few extra goroutines spun up between ticks is ordinary background churn and shouldn't be
+// flagged on its own; this is the count-scale analog of the duration-scale floor the other
+// probe series use.
+const goroutineOutlierFloor = 4
+
+// outlierThresholdCount is outlierThreshold for goroutineHist, whose buckets hold raw
+// goroutine-count deltas encoded as microseconds (see goroutineHist's doc comment) rather than
+// an actual duration, so its Percentiles need converting back to counts before the IQR math.
+func outlierThresholdCount(h *lagHistogram, floor int64) int64 {
+	qs := h.Percentiles(0.25, 0.5, 0.75)
+	q25, q50, q75 := qs[0].Microseconds(), qs[1].Microseconds(), qs[2].Microseconds()
+	add := 3 * (q75 - q25)
+	if add < floor {
+		add = floor
+	}
+	return q50 + add
+}
+
+// Cause names the dominant suspected cause of a lag spike, by checking which runtime/metrics
+// series in d is itself an IQR-outlier (against floor, or goroutineOutlierFloor for the
+// goroutine series) against its own recent history, and
+// returning whichever one is furthest past its threshold. Returns "" if nothing in d looks
+// unusual yet.
+func (p *runtimeProbe) Cause(d RuntimeDelta, floor time.Duration) string {
+	candidates := []struct {
+		label string
+		mag   time.Duration
+		count uint64
+		hi    time.Duration
+	}{
+		{fmt.Sprintf("gc-pause: %v", d.GCPause), d.GCPause, p.gcPauseHist.Count(), outlierThreshold(&p.gcPauseHist, floor)},
+		{fmt.Sprintf("sched-latency: %v", d.SchedLatency), d.SchedLatency, p.schedLatencyHist.Count(), outlierThreshold(&p.schedLatencyHist, floor)},
+		{fmt.Sprintf("mutex-wait: %v", d.MutexWait), d.MutexWait, p.mutexWaitHist.Count(), outlierThreshold(&p.mutexWaitHist, floor)},
+		{
+			fmt.Sprintf("goroutines: %v→%v", d.Goroutines-d.GoroutineDelta, d.Goroutines),
+			time.Duration(absInt64(d.GoroutineDelta)),
+			p.goroutineHist.Count(),
+			time.Duration(outlierThresholdCount(&p.goroutineHist, goroutineOutlierFloor)),
+		},
+	}
+
+	var cause string
+	var bestOver time.Duration
+	for _, c := range candidates {
+		if c.count < 4 {
+			continue
+		}
+		if c.mag < c.hi {
+			continue
+		}
+		if over := c.mag - c.hi; over > bestOver {
+			bestOver = over
+			cause = c.label
+		}
+	}
+
+	return cause
+}